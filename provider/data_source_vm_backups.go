@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"terraform-provider-idcloudhost/provider/schemas"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func DataSourceVmBackups() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataVmBackupsRead,
+		Schema:      schemas.DataVmBackupsSchema,
+	}
+}
+
+// list existing backups (restore points) of a vm
+func dataVmBackupsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/backups"
+	generatedUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		generatedUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		generatedUrl = baseUrl + version + "/" + location + path
+	}
+	vm_uuid := d.Get("vm_uuid").(string)
+
+	fullUrl, err := url.Parse(generatedUrl)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	client := &http.Client{}
+	queryParams := url.Values{}
+	queryParams.Add("uuid", vm_uuid)
+	fullUrl.RawQuery = queryParams.Encode()
+	req, err := http.NewRequest("GET", fullUrl.String(), nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	}
+	var result []interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	backups := []interface{}{}
+	for _, backup := range result {
+		if backupMap, ok := backup.(map[string]interface{}); ok {
+			backup_uuid, _ := backupMap["uuid"].(string)
+			size, _ := backupMap["size"].(float64)
+			created_at, _ := backupMap["created_at"].(string)
+			status, _ := backupMap["status"].(string)
+			backups = append(backups, map[string]interface{}{
+				"backup_uuid": backup_uuid,
+				"size":        int(size),
+				"created_at":  created_at,
+				"status":      status,
+			})
+		}
+	}
+
+	d.SetId(vm_uuid)
+	d.Set("backups", backups)
+
+	return nil
+}
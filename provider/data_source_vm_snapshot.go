@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"terraform-provider-idcloudhost/provider/schemas"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func DataSourceVmSnapshot() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataVmSnapshotRead,
+		Schema:      schemas.DataVmSnapshotSchema,
+	}
+}
+
+// find the latest available snapshot of a vm, pending or failed ones are skipped
+func dataVmSnapshotRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	vm_uuid := d.Get("vm_uuid").(string)
+
+	snapshots, err := listVmSnapshots(*config, location, vm_uuid)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	var latest map[string]interface{}
+	latestCreatedAt := ""
+	for _, snapshot := range snapshots {
+		if snapshotMap, ok := snapshot.(map[string]interface{}); ok {
+			if status, _ := snapshotMap["status"].(string); status != "available" {
+				continue
+			}
+			created_at, _ := snapshotMap["created_at"].(string)
+			// created_at is ISO 8601, so string comparison keeps the order
+			if latest == nil || created_at > latestCreatedAt {
+				latest = snapshotMap
+				latestCreatedAt = created_at
+			}
+		}
+	}
+	if latest == nil {
+		return diag.FromErr(fmt.Errorf("no available snapshot found for vm %s", vm_uuid))
+	}
+
+	setVmSnapshot(d, latest)
+	d.SetId(d.Get("snapshot_uuid").(string))
+
+	return nil
+}
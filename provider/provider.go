@@ -32,11 +32,21 @@ func Provider() *schema.Provider {
 			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"idcloudhost_s3":              ResourceStorage(),
-			"idcloudhost_private_network": ResourcePrivateNetwork(),
-			"idcloudhost_float_ip":        ResourceFloatIp(),
-			"idcloudhost_vm":              ResourceVm(),
-			"idcloudhost_loadbalancer":    ResourceLoadBalancer(),
+			"idcloudhost_s3":                   ResourceStorage(),
+			"idcloudhost_private_network":      ResourcePrivateNetwork(),
+			"idcloudhost_float_ip":             ResourceFloatIp(),
+			"idcloudhost_float_ip_association": ResourceFloatIpAssociation(),
+			"idcloudhost_vm":                   ResourceVm(),
+			"idcloudhost_loadbalancer":         ResourceLoadBalancer(),
+			"idcloudhost_firewall":             ResourceFirewall(),
+			"idcloudhost_firewall_attachment":  ResourceFirewallAttachment(),
+			"idcloudhost_vm_snapshot":          ResourceVmSnapshot(),
+			"idcloudhost_vm_backup_policy":     ResourceVmBackupPolicy(),
+			"idcloudhost_vm_disk":              ResourceVmDisk(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"idcloudhost_vm_snapshot": DataSourceVmSnapshot(),
+			"idcloudhost_vm_backups":  DataSourceVmBackups(),
 		},
 		ConfigureContextFunc: contextConfig,
 	}
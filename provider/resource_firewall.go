@@ -0,0 +1,369 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"terraform-provider-idcloudhost/provider/schemas"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceFirewall() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: firewallCreate,
+		ReadContext:   firewallRead,
+		UpdateContext: firewallUpdate,
+		DeleteContext: firewallDelete,
+		Schema:        schemas.FirewallSchema,
+		Importer: &schema.ResourceImporter{
+			State: firewallState,
+		},
+		CustomizeDiff: firewallRulesCustomizeDiff,
+	}
+}
+
+// tcp & udp rules need a port, ranges can not be inverted
+func firewallRulesCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	for _, block := range []string{"ingress", "egress"} {
+		if !d.NewValueKnown(block) {
+			continue
+		}
+		for i, raw := range d.Get(block).([]interface{}) {
+			rule, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			protocol, _ := rule["protocol"].(string)
+			port_start, _ := rule["port_start"].(int)
+			port_end, _ := rule["port_end"].(int)
+			if port_start == 0 && (protocol == "tcp" || protocol == "udp") {
+				return fmt.Errorf("%s.%d: port_start is required for %s rules", block, i, protocol)
+			}
+			if port_end != 0 && port_end < port_start {
+				return fmt.Errorf("%s.%d: port_end %d is lower than port_start %d", block, i, port_end, port_start)
+			}
+		}
+	}
+
+	return nil
+}
+
+// whether each rule of the config omits port_end
+func firewallConfigPortEnds(d *schema.ResourceData, block string) []bool {
+	omitted := []bool{}
+	rawConfig := d.GetRawConfig()
+	if rawConfig.IsNull() || !rawConfig.IsKnown() {
+		return omitted
+	}
+	rawRules := rawConfig.GetAttr(block)
+	if rawRules.IsNull() || !rawRules.IsKnown() {
+		return omitted
+	}
+	for _, rawRule := range rawRules.AsValueSlice() {
+		omitted = append(omitted, rawRule.IsNull() || rawRule.GetAttr("port_end").IsNull())
+	}
+
+	return omitted
+}
+
+// build api rules from ingress & egress blocks
+func expandFirewallRules(d *schema.ResourceData) []map[string]interface{} {
+	rules := []map[string]interface{}{}
+	directions := map[string]string{
+		"ingress": "inbound",
+		"egress":  "outbound",
+	}
+	cidrKeys := map[string]string{
+		"ingress": "source_cidr",
+		"egress":  "destination_cidr",
+	}
+	for _, block := range []string{"ingress", "egress"} {
+		omittedPortEnds := firewallConfigPortEnds(d, block)
+		for i, raw := range d.Get(block).([]interface{}) {
+			rule, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			port_start := rule["port_start"].(int)
+			port_end := rule["port_end"].(int)
+			// state may still hold the port_end of a suppressed diff
+			if port_end == 0 || (i < len(omittedPortEnds) && omittedPortEnds[i]) {
+				port_end = port_start
+			}
+			rules = append(rules, map[string]interface{}{
+				"direction":     directions[block],
+				"protocol":      rule["protocol"].(string),
+				"port_start":    port_start,
+				"port_end":      port_end,
+				"endpoint_spec": []string{rule[cidrKeys[block]].(string)},
+			})
+		}
+	}
+
+	return rules
+}
+
+// split api rules into ingress & egress blocks
+func flattenFirewallRules(rules []interface{}) ([]interface{}, []interface{}) {
+	ingress := []interface{}{}
+	egress := []interface{}{}
+	for _, raw := range rules {
+		rule, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		direction, _ := rule["direction"].(string)
+		protocol, _ := rule["protocol"].(string)
+		port_start, _ := rule["port_start"].(float64)
+		port_end, _ := rule["port_end"].(float64)
+		cidr := "0.0.0.0/0"
+		if endpoints, ok := rule["endpoint_spec"].([]interface{}); ok && len(endpoints) > 0 {
+			if endpoint, ok := endpoints[0].(string); ok {
+				cidr = endpoint
+			}
+		}
+
+		flattened := map[string]interface{}{
+			"protocol":   protocol,
+			"port_start": int(port_start),
+			"port_end":   int(port_end),
+		}
+		if direction == "outbound" {
+			flattened["destination_cidr"] = cidr
+			egress = append(egress, flattened)
+		} else {
+			flattened["source_cidr"] = cidr
+			ingress = append(ingress, flattened)
+		}
+	}
+
+	return ingress, egress
+}
+
+// only accept location from provider config
+func firewallState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
+	d.Set("location", config.DefaultLocation)
+
+	diags := firewallRead(context.Background(), d, m)
+	if diags.HasError() {
+		return nil, fmt.Errorf(diags[0].Summary)
+	}
+	if d.Id() == "" {
+		return nil, fmt.Errorf("firewall not found")
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func firewallCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/network/firewalls"
+	fullUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	data := map[string]interface{}{
+		"display_name": d.Get("name").(string),
+		"rules":        expandFirewallRules(d),
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fullUrl, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	uuid, ok := result["uuid"].(string)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("fail to get generated UUID"))
+	}
+
+	d.SetId(uuid)
+	d.Set("uuid", uuid)
+
+	return firewallRead(ctx, d, m)
+}
+
+func firewallRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/network/firewalls/"
+	fullUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+	uuid := d.Id()
+
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", fullUrl+uuid, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// firewall removed outside terraform
+		d.SetId("")
+		return nil
+	}
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	name, _ := result["display_name"].(string)
+	rules, _ := result["rules"].([]interface{})
+	ingress, egress := flattenFirewallRules(rules)
+
+	d.Set("uuid", uuid)
+	d.Set("name", name)
+	d.Set("ingress", ingress)
+	d.Set("egress", egress)
+
+	return nil
+}
+
+func firewallUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/network/firewalls/"
+	fullUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+	uuid := d.Id()
+
+	if d.HasChanges("name", "ingress", "egress") {
+		data := map[string]interface{}{
+			"display_name": d.Get("name").(string),
+			"rules":        expandFirewallRules(d),
+		}
+
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		client := &http.Client{}
+		req, err := http.NewRequest("PATCH", fullUrl+uuid, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("apikey", apiKey)
+		resp, err := client.Do(req)
+
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode > 299 || resp.StatusCode < 200 {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+		}
+	}
+
+	return firewallRead(ctx, d, m)
+}
+
+func firewallDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/network/firewalls/"
+	fullUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+	uuid := d.Id()
+
+	client := &http.Client{}
+	req, err := http.NewRequest("DELETE", fullUrl+uuid, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	}
+
+	return nil
+}
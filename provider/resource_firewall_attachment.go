@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"terraform-provider-idcloudhost/provider/schemas"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceFirewallAttachment() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: firewallAttachmentCreate,
+		ReadContext:   firewallAttachmentRead,
+		UpdateContext: firewallAttachmentUpdate,
+		DeleteContext: firewallAttachmentDelete,
+		Schema:        schemas.FirewallAttachmentSchema,
+		Importer: &schema.ResourceImporter{
+			State: firewallAttachmentState,
+		},
+	}
+}
+
+// action = "assign" | "unassign"
+func firewallAssignVm(c Config, location string, firewall_uuid string, vm_uuid string, action string) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := fmt.Sprintf("/network/firewalls/%s/%s", firewall_uuid, action)
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	data := map[string]interface{}{
+		"vm_uuid": vm_uuid,
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fullUrl, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(string(bodyBytes))
+	}
+
+	return nil
+}
+
+// only accept location from provider config
+func firewallAttachmentState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
+	d.Set("firewall_uuid", d.Id())
+	d.Set("location", config.DefaultLocation)
+
+	diags := firewallAttachmentRead(context.Background(), d, m)
+	if diags.HasError() {
+		return nil, fmt.Errorf(diags[0].Summary)
+	}
+	if d.Id() == "" {
+		return nil, fmt.Errorf("firewall not found")
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func firewallAttachmentCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	firewall_uuid := d.Get("firewall_uuid").(string)
+
+	for _, vm_uuid := range d.Get("vm_uuids").(*schema.Set).List() {
+		if err := firewallAssignVm(*config, location, firewall_uuid, vm_uuid.(string), "assign"); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(firewall_uuid)
+
+	return firewallAttachmentRead(ctx, d, m)
+}
+
+func firewallAttachmentRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/network/firewalls/"
+	fullUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+	firewall_uuid := d.Id()
+
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", fullUrl+firewall_uuid, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// firewall removed outside terraform
+		d.SetId("")
+		return nil
+	}
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	vm_uuids := []string{}
+	if vmUUIDs, ok := result["vm_uuids"].([]interface{}); ok {
+		for _, vmUUID := range vmUUIDs {
+			if uuidStr, ok := vmUUID.(string); ok {
+				vm_uuids = append(vm_uuids, uuidStr)
+			}
+		}
+	}
+
+	d.Set("firewall_uuid", firewall_uuid)
+	d.Set("vm_uuids", vm_uuids)
+
+	return nil
+}
+
+func firewallAttachmentUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	firewall_uuid := d.Id()
+
+	if d.HasChange("vm_uuids") {
+		oldIntrface, newIntrface := d.GetChange("vm_uuids")
+		oldSet := oldIntrface.(*schema.Set)
+		newSet := newIntrface.(*schema.Set)
+
+		for _, vm_uuid := range oldSet.Difference(newSet).List() {
+			if err := firewallAssignVm(*config, location, firewall_uuid, vm_uuid.(string), "unassign"); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+		for _, vm_uuid := range newSet.Difference(oldSet).List() {
+			if err := firewallAssignVm(*config, location, firewall_uuid, vm_uuid.(string), "assign"); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+
+	return firewallAttachmentRead(ctx, d, m)
+}
+
+func firewallAttachmentDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	firewall_uuid := d.Id()
+
+	for _, vm_uuid := range d.Get("vm_uuids").(*schema.Set).List() {
+		if err := firewallAssignVm(*config, location, firewall_uuid, vm_uuid.(string), "unassign"); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return nil
+}
@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"strings"
 	"terraform-provider-idcloudhost/provider/schemas"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
@@ -27,57 +27,170 @@ func ResourceFloatIp() *schema.Resource {
 	}
 }
 
-// only accept location from provider config
-func flaotIpState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
-	config := m.(*Config)
-	apiKey := config.ApiKey
-	baseUrl := config.BaseUrl
-	defaultLocation := config.DefaultLocation
-	address := d.Id()
+func getFloatIp(c Config, location string, address string) (map[string]interface{}, error) {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
 	version := "/v1"
 	path := "/network/ip_addresses/"
-	generatedUrl := baseUrl + version + path
+	fullUrl := baseUrl + version + path
 	if defaultLocation != "" {
-		generatedUrl = baseUrl + version + "/" + defaultLocation + path
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
 	}
 
-	fullUrl, err := url.Parse(generatedUrl + address)
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", fullUrl+address, nil)
 	if err != nil {
 		return nil, err
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
 
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf(string(bodyBytes))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// action = "assign" | "unassign". vm_uuid is only used on assign
+func floatIpAction(c Config, location string, address string, vm_uuid string, action string) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := fmt.Sprintf("/network/ip_addresses/%s/%s", address, action)
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	var body io.Reader
+	if action == "assign" {
+		data := map[string]interface{}{
+			"vm_uuid": vm_uuid,
+		}
+
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(jsonData)
 	}
 
 	client := &http.Client{}
-	req, err := http.NewRequest("GET", fullUrl.String(), nil)
+	req, err := http.NewRequest("POST", fullUrl, body)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
 	}
 	req.Header.Set("apikey", apiKey)
 	resp, err := client.Do(req)
+
 	if err != nil {
-		return nil, err
+		return err
 	}
-
 	defer resp.Body.Close()
 
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf(string(bodyBytes))
+		return fmt.Errorf(string(bodyBytes))
+	}
+
+	return nil
+}
 
+// set PTR record of the address
+func setFloatIpReverseDns(c Config, location string, address string, reverse_dns string) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := fmt.Sprintf("/network/ip_addresses/%s/rdns", address)
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
 	}
-	var result map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	data := map[string]interface{}{
+		"rdns": reverse_dns,
+	}
+
+	jsonData, err := json.Marshal(data)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	name, _ := result["assigned_to_resource_type"].(string)
-	billing_account_id, _ := result["billing_account_id"].(float64)
+	client := &http.Client{}
+	req, err := http.NewRequest("PUT", fullUrl, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
 
-	d.Set("name", name)
-	d.Set("billing_account_id", billing_account_id)
-	d.Set("address", address)
-	d.Set("location", defaultLocation)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(string(bodyBytes))
+	}
+
+	return nil
+}
+
+// id = ADDRESS or LOCATION/ADDRESS. location default to provider config
+func flaotIpState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
+	location := config.DefaultLocation
+	address := d.Id()
+	if parts := strings.Split(d.Id(), "/"); len(parts) == 2 {
+		location = parts[0]
+		address = parts[1]
+	}
+	if address == "" {
+		return nil, fmt.Errorf("unexpected import id %q, expected ADDRESS or LOCATION/ADDRESS", d.Id())
+	}
+
+	d.SetId(address)
+	d.Set("location", location)
+
+	diags := floatIpRead(context.Background(), d, m)
+	if diags.HasError() {
+		return nil, fmt.Errorf(diags[0].Summary)
+	}
+	if d.Id() == "" {
+		return nil, fmt.Errorf("float ip %s not found", address)
+	}
 
 	return []*schema.ResourceData{d}, nil
 }
@@ -143,50 +256,42 @@ func floatIpCreate(ctx context.Context, d *schema.ResourceData, m interface{}) d
 	d.SetId(address)
 	d.Set("address", address)
 
+	if reverse_dns := d.Get("reverse_dns").(string); reverse_dns != "" {
+		if err := setFloatIpReverseDns(*config, location, address, reverse_dns); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	return floatIpRead(ctx, d, m)
 }
 
 func floatIpRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-
 	config := m.(*Config)
-	apiKey := config.ApiKey
-	baseUrl := config.BaseUrl
-	defaultLocation := config.DefaultLocation
-	address := d.Id()
-	version := "/v1"
-	path := "/network/ip_addresses/"
-	generatedUrl := baseUrl + version + path
 	location := d.Get("location").(string)
-	if defaultLocation != "" {
-		generatedUrl = baseUrl + version + "/" + defaultLocation + path
-	}
-	if location != "" {
-		generatedUrl = baseUrl + version + "/" + location + path
-	}
+	address := d.Id()
 
-	fullUrl, err := url.Parse(generatedUrl + address)
+	result, err := getFloatIp(*config, location, address)
 	if err != nil {
 		return diag.FromErr(err)
-
 	}
-
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", fullUrl.String(), nil)
-	if err != nil {
-		return diag.FromErr(err)
+	if result == nil {
+		// float ip released outside terraform
+		d.SetId("")
+		return nil
 	}
-	req.Header.Set("apikey", apiKey)
-	resp, err := client.Do(req)
 
-	if err != nil {
-		return diag.FromErr(err)
-	}
-	if resp.StatusCode > 299 || resp.StatusCode < 200 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	name, _ := result["name"].(string)
+	billing_account_id, _ := result["billing_account_id"].(float64)
+	assigned_to, _ := result["assigned_to"].(string)
+	assigned_to_resource_type, _ := result["assigned_to_resource_type"].(string)
+	reverse_dns, _ := result["rdns"].(string)
 
-	}
-	defer resp.Body.Close()
+	d.Set("address", address)
+	d.Set("name", name)
+	d.Set("billing_account_id", int(billing_account_id))
+	d.Set("assigned_to", assigned_to)
+	d.Set("assigned_to_resource_type", assigned_to_resource_type)
+	d.Set("reverse_dns", reverse_dns)
 
 	return nil
 }
@@ -240,6 +345,13 @@ func floatIpUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) d
 		defer resp.Body.Close()
 	}
 
+	// removed reverse_dns sends an empty rdns, clearing the PTR record
+	if d.HasChange("reverse_dns") {
+		if err := setFloatIpReverseDns(*config, location, address, d.Get("reverse_dns").(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	return floatIpRead(ctx, d, m)
 }
 
@@ -261,6 +373,30 @@ func floatIpDelete(ctx context.Context, d *schema.ResourceData, m interface{}) d
 	}
 	fullUrl := generatedUrl + address
 
+	// still bound address is only released after an explicit unassign
+	floatIp, err := getFloatIp(*config, location, address)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if floatIp == nil {
+		return nil
+	}
+	if assigned_to, _ := floatIp["assigned_to"].(string); assigned_to != "" {
+		if !d.Get("force_unassign").(bool) {
+			assigned_to_resource_type, _ := floatIp["assigned_to_resource_type"].(string)
+			return diag.Diagnostics{
+				{
+					Severity: diag.Error,
+					Summary:  fmt.Sprintf("float ip %s is still assigned to %s %s", address, assigned_to_resource_type, assigned_to),
+					Detail:   "unassign it first, or set force_unassign = true to unassign it before destroy",
+				},
+			}
+		}
+		if err := floatIpAction(*config, location, address, "", "unassign"); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	client := &http.Client{}
 	req, err := http.NewRequest("DELETE", fullUrl, nil)
 	if err != nil {
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"terraform-provider-idcloudhost/provider/schemas"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceFloatIpAssociation() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: floatIpAssociationCreate,
+		ReadContext:   floatIpAssociationRead,
+		DeleteContext: floatIpAssociationDelete,
+		Schema:        schemas.FloatIpAssociationSchema,
+		Importer: &schema.ResourceImporter{
+			State: floatIpAssociationState,
+		},
+	}
+}
+
+// id = ADDRESS/VM_UUID. only accept location from provider config
+func floatIpAssociationState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("unexpected import id %q, expected ADDRESS/VM_UUID", d.Id())
+	}
+
+	d.Set("address", parts[0])
+	d.Set("vm_uuid", parts[1])
+	d.Set("location", config.DefaultLocation)
+
+	diags := floatIpAssociationRead(context.Background(), d, m)
+	if diags.HasError() {
+		return nil, fmt.Errorf(diags[0].Summary)
+	}
+	if d.Id() == "" {
+		return nil, fmt.Errorf("float ip %s is not assigned to vm %s", parts[0], parts[1])
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func floatIpAssociationCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	address := d.Get("address").(string)
+	vm_uuid := d.Get("vm_uuid").(string)
+
+	// moving the address from another vm require unassign first
+	floatIp, err := getFloatIp(*config, location, address)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if floatIp == nil {
+		return diag.FromErr(fmt.Errorf("float ip %s not found", address))
+	}
+	if assigned_to, _ := floatIp["assigned_to"].(string); assigned_to != "" && assigned_to != vm_uuid {
+		if err := floatIpAction(*config, location, address, "", "unassign"); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	if assigned_to, _ := floatIp["assigned_to"].(string); assigned_to != vm_uuid {
+		if err := floatIpAction(*config, location, address, vm_uuid, "assign"); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(address + "/" + vm_uuid)
+
+	return floatIpAssociationRead(ctx, d, m)
+}
+
+func floatIpAssociationRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	address := d.Get("address").(string)
+	vm_uuid := d.Get("vm_uuid").(string)
+
+	floatIp, err := getFloatIp(*config, location, address)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	// address released or assigned elsewhere outside terraform
+	if assigned_to, _ := floatIp["assigned_to"].(string); floatIp == nil || assigned_to != vm_uuid {
+		d.SetId("")
+		return nil
+	}
+
+	d.SetId(address + "/" + vm_uuid)
+
+	return nil
+}
+
+func floatIpAssociationDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	address := d.Get("address").(string)
+	vm_uuid := d.Get("vm_uuid").(string)
+
+	// already moved to another vm, leave it there
+	floatIp, err := getFloatIp(*config, location, address)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if assigned_to, _ := floatIp["assigned_to"].(string); floatIp == nil || assigned_to != vm_uuid {
+		return nil
+	}
+
+	if err := floatIpAction(*config, location, address, "", "unassign"); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
@@ -8,9 +8,12 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"terraform-provider-idcloudhost/provider/schemas"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -24,32 +27,28 @@ func ResourcePrivateNetwork() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: privateNetworkState,
 		},
+		Timeouts: &schema.ResourceTimeout{
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
 	}
 }
 
-// only accept location from provider config
-func privateNetworkState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
-
-	config := m.(*Config)
-	apiKey := config.ApiKey
-	baseUrl := config.BaseUrl
-	defaultLocation := config.DefaultLocation
+// vms attached to the network, listed from /network/networks
+func findNetworkVms(c Config, location string, network_uuid string) ([]string, error) {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
 	version := "/v1"
-	path := "/network/network/"
-	generatedUrl := baseUrl + version + path
+	path := "/network/networks"
+	fullUrl := baseUrl + version + path
 	if defaultLocation != "" {
-		generatedUrl = baseUrl + version + "/" + defaultLocation + path
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
 	}
-	uuid := d.Id()
-
-	fullUrl, err := url.Parse(generatedUrl + uuid)
-	if err != nil {
-		return nil, err
-
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
 	}
-
 	client := &http.Client{}
-	req, err := http.NewRequest("GET", fullUrl.String(), nil)
+	req, err := http.NewRequest("GET", fullUrl, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -59,25 +58,99 @@ func privateNetworkState(d *schema.ResourceData, m interface{}) ([]*schema.Resou
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf(string(bodyBytes))
-
 	}
-	var result map[string]interface{}
-	bodyBytes, err := io.ReadAll(resp.Body)
+	var result []interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
 	if err := json.Unmarshal(bodyBytes, &result); err != nil {
 		return nil, err
 	}
 
+	vm_uuids := []string{}
+	for _, network := range result {
+		if networkMap, ok := network.(map[string]interface{}); ok {
+			if uuid, _ := networkMap["uuid"].(string); uuid != network_uuid {
+				continue
+			}
+			if vmUUIDs, ok := networkMap["vm_uuids"].([]interface{}); ok {
+				for _, vmUUID := range vmUUIDs {
+					if uuidStr, ok := vmUUID.(string); ok {
+						vm_uuids = append(vm_uuids, uuidStr)
+					}
+				}
+			}
+			break
+		}
+	}
+
+	return vm_uuids, nil
+}
+
+// new vms in the location land in the default network
+func setDefaultPrivateNetwork(c Config, location string, uuid string) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := fmt.Sprintf("/network/network/%s/default", uuid)
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fullUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
 	defer resp.Body.Close()
 
-	name, _ := result["name"].(string)
-	network_uuid, _ := result["network_uuid"].(string)
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(string(bodyBytes))
+	}
 
-	d.Set("name", name)
-	d.Set("uuid", network_uuid)
-	d.Set("location", defaultLocation)
+	return nil
+}
+
+// id = UUID or LOCATION/UUID. location default to provider config
+func privateNetworkState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
+	location := config.DefaultLocation
+	uuid := d.Id()
+	if parts := strings.Split(d.Id(), "/"); len(parts) == 2 {
+		location = parts[0]
+		uuid = parts[1]
+	}
+	if uuid == "" {
+		return nil, fmt.Errorf("unexpected import id %q, expected UUID or LOCATION/UUID", d.Id())
+	}
+
+	d.SetId(uuid)
+	d.Set("location", location)
+
+	diags := privateNetworkRead(context.Background(), d, m)
+	if diags.HasError() {
+		return nil, fmt.Errorf(diags[0].Summary)
+	}
+	if d.Id() == "" {
+		return nil, fmt.Errorf("private network %s not found", uuid)
+	}
+	// config of a default network would otherwise plan set_as_default
+	d.Set("set_as_default", d.Get("is_default").(bool))
 
 	return []*schema.ResourceData{d}, nil
 }
@@ -99,6 +172,9 @@ func privateNetworkCreate(ctx context.Context, d *schema.ResourceData, m interfa
 	}
 
 	name := d.Get("name").(string)
+	cidr := d.Get("cidr").(string)
+	gateway := d.Get("gateway").(string)
+	dns_servers := d.Get("dns_servers").([]interface{})
 
 	fullUrl, err := url.Parse(generatedUrl)
 	if err != nil {
@@ -109,6 +185,15 @@ func privateNetworkCreate(ctx context.Context, d *schema.ResourceData, m interfa
 	client := &http.Client{}
 	queryParams := url.Values{}
 	queryParams.Add("name", name)
+	if cidr != "" {
+		queryParams.Add("subnet", cidr)
+	}
+	if gateway != "" {
+		queryParams.Add("gateway", gateway)
+	}
+	for _, dns_server := range dns_servers {
+		queryParams.Add("dns_servers", dns_server.(string))
+	}
 	fullUrl.RawQuery = queryParams.Encode()
 
 	req, err := http.NewRequest("POST", fullUrl.String(), nil)
@@ -142,6 +227,12 @@ func privateNetworkCreate(ctx context.Context, d *schema.ResourceData, m interfa
 	d.SetId(uuid)
 	d.Set("network_uuid", uuid)
 
+	if d.Get("set_as_default").(bool) {
+		if err := setDefaultPrivateNetwork(*config, location, uuid); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	return privateNetworkRead(ctx, d, m)
 }
 
@@ -180,12 +271,58 @@ func privateNetworkRead(ctx context.Context, d *schema.ResourceData, m interface
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// network removed outside terraform
+		d.SetId("")
+		return nil
+	}
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
 
 	}
-	defer resp.Body.Close()
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	name, _ := result["name"].(string)
+	subnet, _ := result["subnet"].(string)
+	gateway, _ := result["gateway"].(string)
+	dns_servers := []string{}
+	if dnsServers, ok := result["dns_servers"].([]interface{}); ok {
+		for _, dnsServer := range dnsServers {
+			if dnsStr, ok := dnsServer.(string); ok {
+				dns_servers = append(dns_servers, dnsStr)
+			}
+		}
+	}
+	is_default, _ := result["is_default"].(bool)
+	vm_uuids := []string{}
+	if vmUUIDs, ok := result["vm_uuids"].([]interface{}); ok {
+		for _, vmUUID := range vmUUIDs {
+			if uuidStr, ok := vmUUID.(string); ok {
+				vm_uuids = append(vm_uuids, uuidStr)
+			}
+		}
+	}
+
+	d.Set("name", name)
+	d.Set("network_uuid", uuid)
+	d.Set("subnet", subnet)
+	// cidr is sent as subnet on create
+	d.Set("cidr", subnet)
+	d.Set("gateway", gateway)
+	d.Set("dns_servers", dns_servers)
+	d.Set("is_default", is_default)
+	// another network became the default outside terraform
+	if d.Get("set_as_default").(bool) && !is_default {
+		d.Set("set_as_default", false)
+	}
+	d.Set("vm_uuids", vm_uuids)
 
 	return nil
 }
@@ -208,9 +345,10 @@ func privateNetworkUpdate(ctx context.Context, d *schema.ResourceData, m interfa
 	uuid := d.Id()
 	fullUrl := generatedUrl + uuid
 
-	if d.HasChange("name") {
+	if d.HasChanges("name", "dns_servers") {
 		data := map[string]interface{}{
-			"name": d.Get("name").(string),
+			"name":        d.Get("name").(string),
+			"dns_servers": d.Get("dns_servers").([]interface{}),
 		}
 
 		jsonData, err := json.Marshal(data)
@@ -238,6 +376,13 @@ func privateNetworkUpdate(ctx context.Context, d *schema.ResourceData, m interfa
 		defer resp.Body.Close()
 	}
 
+	// unsetting only stop managing it, a location always keep a default network
+	if d.HasChange("set_as_default") && d.Get("set_as_default").(bool) {
+		if err := setDefaultPrivateNetwork(*config, location, uuid); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	return privateNetworkRead(ctx, d, m)
 }
 
@@ -259,6 +404,47 @@ func privateNetworkDelete(ctx context.Context, d *schema.ResourceData, m interfa
 	uuid := d.Id()
 	fullUrl := generatedUrl + uuid
 
+	// attached vms destroyed in the same run may still be detaching, wait for them.
+	// vms still running or stopped after a minute will never detach, fail then
+	activeSince := map[string]time.Time{}
+	err := retry.RetryContext(ctx, d.Timeout(schema.TimeoutDelete), func() *retry.RetryError {
+		vm_uuids, err := findNetworkVms(*config, location, uuid)
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		if len(vm_uuids) == 0 {
+			return nil
+		}
+		active := []string{}
+		for _, vm_uuid := range vm_uuids {
+			vm, err := getVm(*config, location, vm_uuid)
+			if err != nil {
+				return retry.NonRetryableError(err)
+			}
+			if vm == nil {
+				continue
+			}
+			status, _ := vm["status"].(string)
+			if status != "running" && status != "stopped" {
+				delete(activeSince, vm_uuid)
+				continue
+			}
+			if _, ok := activeSince[vm_uuid]; !ok {
+				activeSince[vm_uuid] = time.Now()
+			}
+			if time.Since(activeSince[vm_uuid]) > time.Minute {
+				active = append(active, vm_uuid)
+			}
+		}
+		if len(active) > 0 {
+			return retry.NonRetryableError(fmt.Errorf("private network %s still has vms attached: %s", uuid, strings.Join(active, ", ")))
+		}
+		return retry.RetryableError(fmt.Errorf("private network %s still has vms detaching: %s", uuid, strings.Join(vm_uuids, ", ")))
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
 	client := &http.Client{}
 	req, err := http.NewRequest("DELETE", fullUrl, nil)
 	if err != nil {
@@ -2,18 +2,26 @@ package provider
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/big"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 	"terraform-provider-idcloudhost/provider/schemas"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
@@ -27,10 +35,52 @@ func ResourceVm() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: vmState,
 		},
+		CustomizeDiff: customdiff.All(
+			vmDisksCustomizeDiff,
+			vmPasswordCustomizeDiff,
+			vmLimitsCustomizeDiff,
+			vmOsCustomizeDiff,
+			vmNetworkCustomizeDiff,
+		),
+		Timeouts: &schema.ResourceTimeout{
+			Update: schema.DefaultTimeout(20 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+	}
+}
+
+// reject shrinking disks at plan time, or recreate the vm when allowed
+func vmDisksCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" || !d.HasChange("disks") || !d.NewValueKnown("disks") {
+		return nil
 	}
+	oldIntrface, newIntrface := d.GetChange("disks")
+	oldDisks := oldIntrface.(int)
+	newDisks := newIntrface.(int)
+	if newDisks >= oldDisks {
+		return nil
+	}
+	if d.Get("allow_disk_shrink_replace").(bool) {
+		return d.ForceNew("disks")
+	}
+
+	return fmt.Errorf("disks can not be decreased from %d to %d GB. set allow_disk_shrink_replace = true to recreate the vm instead", oldDisks, newDisks)
 }
 
 func findPrivateNetwork(c Config, uuid string) (string, error) {
+	private_network_uuids, err := findPrivateNetworks(c, "", uuid)
+	if err != nil {
+		return "", err
+	}
+	if len(private_network_uuids) == 0 {
+		return "", nil
+	}
+
+	return private_network_uuids[0], nil
+}
+
+// every private network the vm is attached to
+func findPrivateNetworks(c Config, location string, uuid string) ([]string, error) {
 	apiKey := c.ApiKey
 	baseUrl := c.BaseUrl
 	defaultLocation := c.DefaultLocation
@@ -40,40 +90,43 @@ func findPrivateNetwork(c Config, uuid string) (string, error) {
 	if defaultLocation != "" {
 		fullUrl = baseUrl + version + "/" + defaultLocation + path
 	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
 	client := &http.Client{}
 	req, err := http.NewRequest("GET", fullUrl, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Set("apikey", apiKey)
 	resp, err := client.Do(req)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf(string(bodyBytes))
+		return nil, fmt.Errorf(string(bodyBytes))
 	}
-	private_network_uuid := ""
+	private_network_uuids := []string{}
 	var result []interface{}
 	bodyBytes, _ := io.ReadAll(resp.Body)
 	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Iterate over the networks and extract the VM UUIDs
-out:
 	for _, network := range result {
 		if networkMap, ok := network.(map[string]interface{}); ok {
 			if vmUUIDs, ok := networkMap["vm_uuids"].([]interface{}); ok {
 				for _, vmUUID := range vmUUIDs {
 					if uuidStr, ok := vmUUID.(string); ok {
 						if uuidStr == uuid {
-							private_network_uuid = networkMap["uuid"].(string)
-							break out
+							network_uuid, _ := networkMap["uuid"].(string)
+							private_network_uuids = append(private_network_uuids, network_uuid)
+							break
 						}
 					}
 				}
@@ -81,10 +134,54 @@ out:
 		}
 	}
 
-	return private_network_uuid, nil
+	return private_network_uuids, nil
+}
+
+// action = "attach" | "detach"
+func vmNetworkAction(c Config, location string, uuid string, network_uuid string, fixed_ip string, action string) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/network/" + action
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	form := url.Values{}
+	form.Add("uuid", uuid)
+	form.Add("network_uuid", network_uuid)
+	if fixed_ip != "" {
+		form.Add("ip_address", fixed_ip)
+	}
+	req, err := http.NewRequest("POST", fullUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.PostForm = form
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(string(bodyBytes))
+	}
+
+	return nil
 }
 
-func findFloatIp(c Config, uuid string) (string, error) {
+func findFloatIp(c Config, location string, uuid string) (string, error) {
 
 	apiKey := c.ApiKey
 	baseUrl := c.BaseUrl
@@ -95,6 +192,9 @@ func findFloatIp(c Config, uuid string) (string, error) {
 	if defaultLocation != "" {
 		fullUrl = baseUrl + version + "/" + defaultLocation + path
 	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
 	client := &http.Client{}
 	req, err := http.NewRequest("GET", fullUrl, nil)
 	if err != nil {
@@ -133,7 +233,492 @@ func findFloatIp(c Config, uuid string) (string, error) {
 	return floatip_address, nil
 }
 
-// only accept location from provider config
+func getVm(c Config, location string, uuid string) (map[string]interface{}, error) {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm"
+	generatedUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		generatedUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		generatedUrl = baseUrl + version + "/" + location + path
+	}
+
+	fullUrl, err := url.Parse(generatedUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+	queryParams := url.Values{}
+	queryParams.Add("uuid", uuid)
+	fullUrl.RawQuery = queryParams.Encode()
+	req, err := http.NewRequest("GET", fullUrl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf(string(bodyBytes))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// changing os recreate the vm unless it can be reinstalled in place
+func vmOsCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+	if d.Get("rebuild_on_os_change").(bool) {
+		if !d.HasChanges("os_name", "os_version") || !d.NewValueKnown("password") || !d.NewValueKnown("ssh_keys") {
+			return nil
+		}
+		// reinstall wipe the disk, it needs a way to log in again
+		if d.Get("password").(string) == "" && d.Get("generated_password").(string) == "" && len(d.Get("ssh_keys").([]interface{})) == 0 {
+			return fmt.Errorf("rebuilding the vm on os change requires password, generate_password or ssh_keys to log in")
+		}
+		return nil
+	}
+	for _, key := range []string{"os_name", "os_version"} {
+		if d.HasChange(key) {
+			if err := d.ForceNew(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// primary network is the first network_interface, or private_network_uuid without them
+func vmPrimaryNetwork(network_interfaces []interface{}, private_network_uuid string) (string, string) {
+	if len(network_interfaces) > 0 {
+		if primary, ok := network_interfaces[0].(map[string]interface{}); ok {
+			network_uuid, _ := primary["network_uuid"].(string)
+			fixed_ip, _ := primary["fixed_ip"].(string)
+			return network_uuid, fixed_ip
+		}
+	}
+
+	return private_network_uuid, ""
+}
+
+// primary network can not be detached, changing it recreate the vm.
+// switching between private_network_uuid and network_interface keep the vm
+func vmNetworkCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" || !d.HasChange("network_interface") || !d.NewValueKnown("network_interface") {
+		return nil
+	}
+	oldIntrface, newIntrface := d.GetChange("network_interface")
+	oldInterfaces := oldIntrface.([]interface{})
+	newInterfaces := newIntrface.([]interface{})
+	oldPrivateNetwork, newPrivateNetwork := d.GetChange("private_network_uuid")
+	oldPrimary, oldFixedIp := vmPrimaryNetwork(oldInterfaces, oldPrivateNetwork.(string))
+	newPrimary, newFixedIp := vmPrimaryNetwork(newInterfaces, newPrivateNetwork.(string))
+
+	changed := oldPrimary != newPrimary
+	if len(oldInterfaces) > 0 && len(newInterfaces) > 0 && oldFixedIp != newFixedIp {
+		changed = true
+	}
+	// fixed_ip of a primary network previously set by private_network_uuid
+	if len(oldInterfaces) == 0 && newFixedIp != "" && newFixedIp != d.Get("private_ipv4").(string) {
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	for _, key := range []string{"network_interface.0.network_uuid", "network_interface.0.fixed_ip", "network_interface"} {
+		if d.HasChange(key) {
+			return d.ForceNew(key)
+		}
+	}
+
+	return nil
+}
+
+// new vm need a way to login: password, ssh_keys or a generated password
+func vmPasswordCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if !d.NewValueKnown("password") || !d.NewValueKnown("ssh_keys") {
+		return nil
+	}
+	if d.Id() != "" {
+		// enabling generate_password on an existing vm reset its password
+		if d.HasChange("generate_password") && d.Get("generate_password").(bool) && d.Get("password").(string) == "" && d.Get("generated_password").(string) == "" {
+			return d.SetNewComputed("generated_password")
+		}
+		return nil
+	}
+	if d.Get("password").(string) != "" || len(d.Get("ssh_keys").([]interface{})) > 0 || d.Get("generate_password").(bool) {
+		return nil
+	}
+
+	return fmt.Errorf("password is required unless ssh_keys or generate_password = true is set")
+}
+
+func getVmLimits(c Config, location string) (map[string]interface{}, error) {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/limits"
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", fullUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf(string(bodyBytes))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// check vcpu, ram & disks against the account/location limits.
+// limits are best effort, an unreachable api only skips the check
+func vmLimitsCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	config, ok := m.(*Config)
+	if !ok || config == nil || config.ApiKey == "" {
+		return nil
+	}
+	if d.Id() != "" && !d.HasChanges("vcpu", "ram", "disks") {
+		return nil
+	}
+	if !d.NewValueKnown("vcpu") || !d.NewValueKnown("ram") || !d.NewValueKnown("disks") || !d.NewValueKnown("location") {
+		return nil
+	}
+
+	limits, err := getVmLimits(*config, d.Get("location").(string))
+	if err != nil {
+		log.Printf("[WARN] skip vm limits check: %s", err)
+		return nil
+	}
+
+	sizes := []struct {
+		field string
+		key   string
+		unit  string
+	}{
+		{"vcpu", "max_vcpu", ""},
+		{"ram", "max_ram", " mb"},
+		{"disks", "max_disks", " gb"},
+	}
+	for _, size := range sizes {
+		limit, ok := limits[size.key].(float64)
+		if !ok || limit <= 0 {
+			continue
+		}
+		value := d.Get(size.field).(int)
+		if value > int(limit) {
+			return fmt.Errorf("%s = %d%s exceeds the limit of %d%s for this account/location", size.field, value, size.unit, int(limit), size.unit)
+		}
+	}
+
+	return nil
+}
+
+// random password with lower, upper case letters and digits
+func generateVmPassword(length int) (string, error) {
+	const charset = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	for {
+		password := make([]byte, length)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+			if err != nil {
+				return "", err
+			}
+			password[i] = charset[n.Int64()]
+		}
+		// retry until every class appears at least once
+		if strings.ContainsAny(string(password), "abcdefghijkmnopqrstuvwxyz") &&
+			strings.ContainsAny(string(password), "ABCDEFGHJKLMNPQRSTUVWXYZ") &&
+			strings.ContainsAny(string(password), "23456789") {
+			return string(password), nil
+		}
+	}
+}
+
+func resetVmPassword(c Config, location string, uuid string, username string, password string) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/reset_password"
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	form := url.Values{}
+	form.Add("uuid", uuid)
+	form.Add("username", username)
+	if password != "" {
+		form.Add("password", password)
+	}
+	req, err := http.NewRequest("POST", fullUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.PostForm = form
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(string(bodyBytes))
+	}
+
+	return nil
+}
+
+// ssh_keys & user_data, sent on create and reinstall
+func addVmProvisionForm(d *schema.ResourceData, form url.Values) error {
+	user_data, err := decodeUserData(d.Get("user_data").(string))
+	if err != nil {
+		return fmt.Errorf("fail to decode user_data: %s", err)
+	}
+	ssh_keys := []string{}
+	for _, key := range d.Get("ssh_keys").([]interface{}) {
+		ssh_keys = append(ssh_keys, strings.TrimSpace(key.(string)))
+	}
+	if user_data != "" {
+		form.Add("cloud_init", user_data)
+	}
+	if len(ssh_keys) > 0 {
+		// authorized_keys format, one key per line
+		form.Add("public_key", strings.Join(ssh_keys, "\n"))
+	}
+
+	return nil
+}
+
+func reinstallVm(c Config, location string, uuid string, form url.Values) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/reinstall"
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	form.Set("uuid", uuid)
+	req, err := http.NewRequest("POST", fullUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.PostForm = form
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(string(bodyBytes))
+	}
+
+	return nil
+}
+
+// action = "start" | "stop" | "force_stop" | "reboot" | "reset"
+func vmPowerAction(c Config, location string, uuid string, action string) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/" + action
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	form := url.Values{}
+	form.Add("uuid", uuid)
+	req, err := http.NewRequest("POST", fullUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.PostForm = form
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(string(bodyBytes))
+	}
+
+	return nil
+}
+
+func waitVmStatus(ctx context.Context, c Config, location string, uuid string, target string, timeout time.Duration) error {
+	stateConf := &retry.StateChangeConf{
+		Target: []string{target},
+		Refresh: func() (interface{}, string, error) {
+			vm, err := getVm(c, location, uuid)
+			if err != nil {
+				return nil, "", err
+			}
+			if vm == nil {
+				return nil, "", fmt.Errorf("vm %s not found", uuid)
+			}
+			status, _ := vm["status"].(string)
+			return vm, status, nil
+		},
+		Timeout:    timeout,
+		Delay:      5 * time.Second,
+		MinTimeout: 5 * time.Second,
+	}
+	if _, err := stateConf.WaitForStateContext(ctx); err != nil {
+		return fmt.Errorf("error waiting for vm %s to be %s: %s", uuid, target, err)
+	}
+
+	return nil
+}
+
+// reboot is asynchronous, the vm keeps reporting running until it goes down.
+// a reboot finishing between two polls is never seen, give up after timeout
+func waitVmRestarting(ctx context.Context, c Config, location string, uuid string, timeout time.Duration) error {
+	stateConf := &retry.StateChangeConf{
+		Pending: []string{"running"},
+		Target:  []string{"restarting"},
+		Refresh: func() (interface{}, string, error) {
+			vm, err := getVm(c, location, uuid)
+			if err != nil {
+				return nil, "", err
+			}
+			if vm == nil {
+				return nil, "", fmt.Errorf("vm %s not found", uuid)
+			}
+			if status, _ := vm["status"].(string); status == "running" {
+				return vm, "running", nil
+			}
+			return vm, "restarting", nil
+		},
+		Timeout:    timeout,
+		MinTimeout: 2 * time.Second,
+	}
+	_, err := stateConf.WaitForStateContext(ctx)
+	var timeoutErr *retry.TimeoutError
+	if err != nil && !errors.As(err, &timeoutErr) {
+		return fmt.Errorf("error waiting for vm %s to restart: %s", uuid, err)
+	}
+
+	return nil
+}
+
+// boot disk is flagged as primary, fallback to the first storage
+func findBootDisk(storage interface{}) map[string]interface{} {
+	storageArray, ok := storage.([]interface{})
+	if !ok || len(storageArray) == 0 {
+		return nil
+	}
+	for _, disk := range storageArray {
+		if diskMap, ok := disk.(map[string]interface{}); ok {
+			if primary, _ := diskMap["primary"].(bool); primary {
+				return diskMap
+			}
+		}
+	}
+	firstStorage, _ := storageArray[0].(map[string]interface{})
+
+	return firstStorage
+}
+
+// accept plain text, base64 or gzip+base64 (e.g. cloudinit_config output) user_data
+func decodeUserData(user_data string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(user_data)
+	if err != nil {
+		// not base64, send as is
+		return user_data, nil
+	}
+	if len(decoded) > 1 && decoded[0] == 0x1f && decoded[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		plain, err := io.ReadAll(reader)
+		if err != nil {
+			return "", err
+		}
+		return string(plain), nil
+	}
+
+	return string(decoded), nil
+}
+
+// id = UUID or LOCATION/UUID. location default to provider config
 func vmState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
 
 	config := m.(*Config)
@@ -142,11 +727,19 @@ func vmState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, err
 	defaultLocation := config.DefaultLocation
 	version := "/v1"
 	path := "/user-resource/vm"
+	location := defaultLocation
+	uuid := d.Id()
+	if parts := strings.Split(d.Id(), "/"); len(parts) == 2 {
+		location = parts[0]
+		uuid = parts[1]
+	}
+	if uuid == "" {
+		return nil, fmt.Errorf("unexpected import id %q, expected UUID or LOCATION/UUID", d.Id())
+	}
 	generatedUrl := baseUrl + version + path
-	if defaultLocation != "" {
-		generatedUrl = baseUrl + version + "/" + defaultLocation + path
+	if location != "" {
+		generatedUrl = baseUrl + version + "/" + location + path
 	}
-	uuid := d.Id()
 
 	fullUrl, err := url.Parse(generatedUrl)
 	if err != nil {
@@ -181,16 +774,28 @@ func vmState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, err
 	name, _ := result["name"].(string)
 	billing_account_id, _ := result["billing_account"].(float64)
 	username, _ := result["username"].(string)
-	password := "<Hidden>"
 	os_name, _ := result["os_name"].(string)
 	os_version, _ := result["os_version"].(string)
 	vcpu, _ := result["vcpu"].(float64)
 	ram, _ := result["memory"].(float64)
-	private_network_uuid, err := findPrivateNetwork(*config, uuid)
+	private_network_uuids, err := findPrivateNetworks(*config, location, uuid)
 	if err != nil {
 		return nil, err
 	}
-	float_ip_address, err := findFloatIp(*config, uuid)
+	private_network_uuid := ""
+	if len(private_network_uuids) > 0 {
+		private_network_uuid = private_network_uuids[0]
+	}
+	network_interfaces := []interface{}{}
+	if len(private_network_uuids) > 1 {
+		for _, network_uuid := range private_network_uuids {
+			network_interfaces = append(network_interfaces, map[string]interface{}{
+				"network_uuid": network_uuid,
+				"fixed_ip":     "",
+			})
+		}
+	}
+	float_ip_address, err := findFloatIp(*config, location, uuid)
 	if err != nil {
 		return nil, err
 	}
@@ -199,33 +804,30 @@ func vmState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, err
 
 	disks_uuid := ""
 	disk_size := 0
-	// Navigate the map to get the value of storage[0].uuid
-	if storageArray, ok := result["storage"].([]interface{}); ok {
-		if firstStorage, ok := storageArray[0].(map[string]interface{}); ok {
-			if storageUuid, ok := firstStorage["uuid"].(string); ok {
-				disks_uuid = storageUuid
-				foundDisk, _ := firstStorage["size"].(float64)
-				disk_size = int(foundDisk)
-			}
-		}
+	// extra disks are managed by idcloudhost_vm_disk, only read the boot disk
+	if bootDisk := findBootDisk(result["storage"]); bootDisk != nil {
+		disks_uuid, _ = bootDisk["uuid"].(string)
+		foundDisk, _ := bootDisk["size"].(float64)
+		disk_size = int(foundDisk)
 	}
 	if disks_uuid == "" {
 		return nil, fmt.Errorf("fail to get generated storage UUID")
 	}
 
+	d.SetId(uuid)
 	d.Set("uuid", uuid)
 	d.Set("disks_uuid", disks_uuid)
-	d.Set("location", defaultLocation)
+	d.Set("location", location)
 	d.Set("name", name)
 	d.Set("billing_account_id", billing_account_id)
 	d.Set("username", username)
-	d.Set("password", password)
 	d.Set("os_name", os_name)
 	d.Set("os_version", os_version)
 	d.Set("vcpu", int(vcpu))
 	d.Set("ram", int(ram))
 	d.Set("disks", disk_size)
 	d.Set("private_network_uuid", private_network_uuid)
+	d.Set("network_interface", network_interfaces)
 	d.Set("float_ip_address", float_ip_address)
 	d.Set("desired_status", desired_status)
 
@@ -253,9 +855,27 @@ func vmCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 	billing_account_id := d.Get("billing_account_id").(int)
 	username := d.Get("username").(string)
 	password := d.Get("password").(string)
+	generated_password := ""
+	if password == "" && d.Get("generate_password").(bool) {
+		generated, err := generateVmPassword(20)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		generated_password = generated
+		password = generated
+	}
 	private_network_uuid := d.Get("private_network_uuid").(string)
+	network_interfaces := d.Get("network_interface").([]interface{})
+	primary_fixed_ip := ""
+	if len(network_interfaces) > 0 {
+		primary := network_interfaces[0].(map[string]interface{})
+		private_network_uuid = primary["network_uuid"].(string)
+		primary_fixed_ip = primary["fixed_ip"].(string)
+	}
 	os_name := d.Get("os_name").(string)
 	os_version := d.Get("os_version").(string)
+	source_snapshot_uuid := d.Get("source_snapshot_uuid").(string)
+	clone_from_vm_uuid := d.Get("clone_from_vm_uuid").(string)
 	vcpu := d.Get("vcpu").(int)
 	ram := d.Get("ram").(int)
 	disks := d.Get("disks").(int)
@@ -267,12 +887,25 @@ func vmCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 	form.Add("username", username)
 	form.Add("password", password)
 	form.Add("network_uuid", private_network_uuid)
-	form.Add("os_name", os_name)
-	form.Add("os_version", os_version)
+	if primary_fixed_ip != "" {
+		form.Add("private_ipv4", primary_fixed_ip)
+	}
+	// build from os image, snapshot or an existing vm
+	if source_snapshot_uuid != "" {
+		form.Add("source_replica", source_snapshot_uuid)
+	} else if clone_from_vm_uuid != "" {
+		form.Add("source_uuid", clone_from_vm_uuid)
+	} else {
+		form.Add("os_name", os_name)
+		form.Add("os_version", os_version)
+	}
 	form.Add("vcpu", strconv.Itoa(vcpu))
 	form.Add("ram", strconv.Itoa(ram))
 	form.Add("disks", strconv.Itoa(disks))
-	form.Add("reserve_public_ip", "false")
+	form.Add("reserve_public_ip", strconv.FormatBool(d.Get("reserve_public_ip").(bool)))
+	if err := addVmProvisionForm(d, form); err != nil {
+		return diag.FromErr(err)
+	}
 	req, err := http.NewRequest("POST", fullUrl, strings.NewReader(form.Encode()))
 	if err != nil {
 		log.Fatal(err)
@@ -307,13 +940,8 @@ func vmCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 	}
 
 	disks_uuid := ""
-	// Navigate the map to get the value of storage[0].uuid
-	if storageArray, ok := result["storage"].([]interface{}); ok {
-		if firstStorage, ok := storageArray[0].(map[string]interface{}); ok {
-			if storageUuid, ok := firstStorage["uuid"].(string); ok {
-				disks_uuid = storageUuid
-			}
-		}
+	if bootDisk := findBootDisk(result["storage"]); bootDisk != nil {
+		disks_uuid, _ = bootDisk["uuid"].(string)
 	}
 	if disks_uuid == "" {
 		return diag.FromErr(fmt.Errorf("fail to get generated storage UUID"))
@@ -322,6 +950,29 @@ func vmCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 	d.SetId(uuid)
 	d.Set("uuid", uuid)
 	d.Set("disks_uuid", disks_uuid)
+	d.Set("generated_password", generated_password)
+	d.Set("private_network_uuid", private_network_uuid)
+
+	if float_ip_address := d.Get("float_ip_address").(string); float_ip_address != "" {
+		if err := floatIpAction(*config, location, float_ip_address, uuid, "assign"); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// attach additional networks
+	if len(network_interfaces) > 1 {
+		for _, raw := range network_interfaces[1:] {
+			network_interface := raw.(map[string]interface{})
+			err := vmNetworkAction(*config, location, uuid,
+				network_interface["network_uuid"].(string),
+				network_interface["fixed_ip"].(string),
+				"attach",
+			)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
 
 	return vmRead(ctx, d, m)
 }
@@ -364,12 +1015,74 @@ func vmRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Dia
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// vm removed outside terraform
+		d.SetId("")
+		return nil
+	}
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
 
 	}
-	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	private_ipv4, _ := result["private_ipv4"].(string)
+	mac_address, _ := result["mac"].(string)
+	hostname, _ := result["hostname"].(string)
+	created_at, _ := result["created_at"].(string)
+	status, _ := result["status"].(string)
+	public_ipv4, _ := result["public_ipv4"].(string)
+	if public_ipv4 == "" {
+		public_ipv4, err = findFloatIp(*config, location, uuid)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if network_interfaces := d.Get("network_interface").([]interface{}); len(network_interfaces) > 0 {
+		private_network_uuids, err := findPrivateNetworks(*config, location, uuid)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		attached := map[string]bool{}
+		for _, network_uuid := range private_network_uuids {
+			attached[network_uuid] = true
+		}
+		// keep configured order & fixed_ip, drop detached and append unknown networks
+		refreshed := []interface{}{}
+		for _, raw := range network_interfaces {
+			network_interface := raw.(map[string]interface{})
+			network_uuid := network_interface["network_uuid"].(string)
+			if attached[network_uuid] {
+				refreshed = append(refreshed, network_interface)
+				delete(attached, network_uuid)
+			}
+		}
+		for _, network_uuid := range private_network_uuids {
+			if attached[network_uuid] {
+				refreshed = append(refreshed, map[string]interface{}{
+					"network_uuid": network_uuid,
+					"fixed_ip":     "",
+				})
+			}
+		}
+		d.Set("network_interface", refreshed)
+	}
+
+	d.Set("private_ipv4", private_ipv4)
+	d.Set("mac_address", mac_address)
+	d.Set("public_ipv4", public_ipv4)
+	d.Set("hostname", hostname)
+	d.Set("created_at", created_at)
+	d.Set("status", status)
 
 	return nil
 }
@@ -392,55 +1105,77 @@ func vmUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 	disks := d.Get("disks").(int)
 	desired_status := d.Get("desired_status").(string)
 
-	if d.HasChange("desired_status") {
-		unregisteredStatus := true
-		statusPath := "start"
-		if desired_status == "stopped" {
-			unregisteredStatus = false
-			statusPath = "stop"
+	// reinstall keep the uuid, disks & network attachments
+	if d.HasChanges("os_name", "os_version") {
+		form := url.Values{}
+		form.Add("os_name", d.Get("os_name").(string))
+		form.Add("os_version", d.Get("os_version").(string))
+		form.Add("username", d.Get("username").(string))
+		password := d.Get("password").(string)
+		if password == "" {
+			password = d.Get("generated_password").(string)
+		}
+		if password != "" {
+			form.Add("password", password)
+		}
+		if err := addVmProvisionForm(d, form); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := reinstallVm(*config, location, uuid, form); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := waitVmStatus(ctx, *config, location, uuid, "running", d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return diag.FromErr(err)
 		}
+	}
 
-		if desired_status == "running" {
-			unregisteredStatus = false
+	if d.HasChange("desired_status") && desired_status != "" {
+		action := "start"
+		target := "running"
+		if desired_status == "stopped" {
+			action = "stop"
+			target = "stopped"
 		}
-		if desired_status != "" && unregisteredStatus {
-			return diag.FromErr(fmt.Errorf("unregistered desired_status"))
+		if desired_status == "force_stop" {
+			action = "force_stop"
+			target = "stopped"
 		}
-		if !unregisteredStatus {
-			version = "/v1"
-			path = "/user-resource/vm/" + statusPath
-			fullUrl = baseUrl + version + path
-			if defaultLocation != "" {
-				fullUrl = baseUrl + version + "/" + defaultLocation + path
-			}
-			if location != "" {
-				fullUrl = baseUrl + version + "/" + location + path
-			}
-			client := &http.Client{}
-			form := url.Values{}
-			form.Add("uuid", uuid)
-			req, err := http.NewRequest("POST", fullUrl, strings.NewReader(form.Encode()))
-			if err != nil {
-				return diag.FromErr(err)
-			}
-			req.PostForm = form
-			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-			req.Header.Set("apikey", apiKey)
-			resp, err := client.Do(req)
+		if err := vmPowerAction(*config, location, uuid, action); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := waitVmStatus(ctx, *config, location, uuid, target, d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
 
+	if d.HasChanges("name", "ram", "vcpu", "billing_account_id") {
+		// resizing require a stopped vm, stop it and restore the power state afterward
+		restart := false
+		if d.HasChanges("ram", "vcpu") {
+			vm, err := getVm(*config, location, uuid)
 			if err != nil {
 				return diag.FromErr(err)
 			}
-
-			if resp.StatusCode > 299 || resp.StatusCode < 200 {
-				bodyBytes, _ := io.ReadAll(resp.Body)
-				return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+			if vm == nil {
+				return diag.FromErr(fmt.Errorf("vm %s not found", uuid))
+			}
+			status, _ := vm["status"].(string)
+			if status == "running" && !d.Get("allow_stopping_for_update").(bool) {
+				return diag.FromErr(fmt.Errorf("vm %s must be stopped to change vcpu or ram. set allow_stopping_for_update = true or desired_status = \"stopped\"", uuid))
+			}
+			if status != "stopped" && d.Get("allow_stopping_for_update").(bool) {
+				if status == "running" {
+					if err := vmPowerAction(*config, location, uuid, "stop"); err != nil {
+						return diag.FromErr(err)
+					}
+					restart = desired_status != "stopped" && desired_status != "force_stop"
+				}
+				if err := waitVmStatus(ctx, *config, location, uuid, "stopped", d.Timeout(schema.TimeoutUpdate)); err != nil {
+					return diag.FromErr(err)
+				}
 			}
-			defer resp.Body.Close()
 		}
-	}
 
-	if d.HasChanges("name", "ram", "vcpu") {
 		path = "/user-resource/vm"
 		version = "/v1"
 		fullUrl = baseUrl + version + path
@@ -454,6 +1189,7 @@ func vmUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 		form := url.Values{}
 		form.Add("uuid", uuid)
 		form.Add("name", name)
+		form.Add("billing_account_id", strconv.Itoa(d.Get("billing_account_id").(int)))
 		form.Add("ram", strconv.Itoa(ram))
 		form.Add("vcpu", strconv.Itoa(vcpu))
 		req, err := http.NewRequest("PATCH", fullUrl, strings.NewReader(form.Encode()))
@@ -474,6 +1210,74 @@ func vmUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 			return diag.FromErr(fmt.Errorf(string(bodyBytes)))
 		}
 		defer resp.Body.Close()
+
+		if restart {
+			if err := vmPowerAction(*config, location, uuid, "start"); err != nil {
+				return diag.FromErr(err)
+			}
+			if err := waitVmStatus(ctx, *config, location, uuid, "running", d.Timeout(schema.TimeoutUpdate)); err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+
+	if d.HasChange("network_interface") {
+		// primary network stays attached, only the other networks are attached/detached
+		primary := d.Get("private_network_uuid").(string)
+		oldIntrface, newIntrface := d.GetChange("network_interface")
+		oldInterfaces := map[string]string{}
+		for _, raw := range oldIntrface.([]interface{}) {
+			network_interface := raw.(map[string]interface{})
+			oldInterfaces[network_interface["network_uuid"].(string)] = network_interface["fixed_ip"].(string)
+		}
+		newInterfaces := map[string]string{}
+		for _, raw := range newIntrface.([]interface{}) {
+			network_interface := raw.(map[string]interface{})
+			newInterfaces[network_interface["network_uuid"].(string)] = network_interface["fixed_ip"].(string)
+		}
+
+		// changed fixed_ip is detached then attached again
+		delete(oldInterfaces, primary)
+		delete(newInterfaces, primary)
+		for network_uuid, fixed_ip := range oldInterfaces {
+			if newFixedIp, ok := newInterfaces[network_uuid]; !ok || newFixedIp != fixed_ip {
+				if err := vmNetworkAction(*config, location, uuid, network_uuid, "", "detach"); err != nil {
+					return diag.FromErr(err)
+				}
+			}
+		}
+		for network_uuid, fixed_ip := range newInterfaces {
+			if oldFixedIp, ok := oldInterfaces[network_uuid]; !ok || oldFixedIp != fixed_ip {
+				if err := vmNetworkAction(*config, location, uuid, network_uuid, fixed_ip, "attach"); err != nil {
+					return diag.FromErr(err)
+				}
+			}
+		}
+	}
+
+	if d.HasChange("password") && d.Get("password").(string) != "" {
+		// imported vm has no way to login in state, only record the configured password
+		oldPassword, _ := d.GetChange("password")
+		imported := oldPassword.(string) == "" && d.Get("generated_password").(string) == "" && len(d.Get("ssh_keys").([]interface{})) == 0
+		if !imported {
+			err := resetVmPassword(*config, location, uuid, d.Get("username").(string), d.Get("password").(string))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+			// explicit password replace the generated one
+			d.Set("generated_password", "")
+		}
+	}
+
+	if d.HasChange("generate_password") && d.Get("generate_password").(bool) && d.Get("password").(string) == "" && d.Get("generated_password").(string) == "" {
+		generated, err := generateVmPassword(20)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if err := resetVmPassword(*config, location, uuid, d.Get("username").(string), generated); err != nil {
+			return diag.FromErr(err)
+		}
+		d.Set("generated_password", generated)
 	}
 
 	if d.HasChange("disks") {
@@ -518,73 +1322,36 @@ func vmUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 
 		if oldAddr != "" {
 			// unassign old ip
-			version = "/v1"
-			path = fmt.Sprintf("/network/ip_addresses/%s/unassign", oldAddr)
-			fullUrl = baseUrl + version + path
-			if defaultLocation != "" {
-				fullUrl = baseUrl + version + "/" + defaultLocation + path
-			}
-			if location != "" {
-				fullUrl = baseUrl + version + "/" + location + path
-			}
-			client := &http.Client{}
-			req, err := http.NewRequest("POST", fullUrl, nil)
-			if err != nil {
-				return diag.FromErr(err)
-			}
-			req.Header.Set("apikey", apiKey)
-			resp, err := client.Do(req)
-
-			if err != nil {
+			if err := floatIpAction(*config, location, oldAddr, "", "unassign"); err != nil {
 				return diag.FromErr(err)
 			}
-
-			if resp.StatusCode > 299 || resp.StatusCode < 200 {
-				bodyBytes, _ := io.ReadAll(resp.Body)
-				return diag.FromErr(fmt.Errorf(string(bodyBytes)))
-			}
-			defer resp.Body.Close()
 		}
 
 		if newAddr != "" {
 			// assign new address
-			data := map[string]interface{}{
-				"vm_uuid": uuid,
-			}
-
-			jsonData, err := json.Marshal(data)
-			if err != nil {
-				return diag.FromErr(err)
-			}
-			version = "/v1"
-			path = fmt.Sprintf("/network/ip_addresses/%s/assign", newAddr)
-			fullUrl = baseUrl + version + path
-			if defaultLocation != "" {
-				fullUrl = baseUrl + version + "/" + defaultLocation + path
-			}
-			if location != "" {
-				fullUrl = baseUrl + version + "/" + location + path
-			}
-			client := &http.Client{}
-			req, err := http.NewRequest("POST", fullUrl, bytes.NewBuffer(jsonData))
-			if err != nil {
-				return diag.FromErr(err)
-			}
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("apikey", apiKey)
-			resp, err := client.Do(req)
-
-			if err != nil {
+			if err := floatIpAction(*config, location, newAddr, uuid, "assign"); err != nil {
 				return diag.FromErr(err)
 			}
+		}
+	}
 
-			if resp.StatusCode > 299 || resp.StatusCode < 200 {
-				bodyBytes, _ := io.ReadAll(resp.Body)
-				return diag.FromErr(fmt.Errorf(string(bodyBytes)))
-			}
-			defer resp.Body.Close()
+	// reboot on trigger change, stopped vm stays stopped
+	if d.HasChange("reboot_trigger") && desired_status != "stopped" && desired_status != "force_stop" {
+		action := "reboot"
+		if d.Get("reboot_type").(string) == "hard" {
+			action = "reset"
+		}
+		if err := vmPowerAction(*config, location, uuid, action); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := waitVmRestarting(ctx, *config, location, uuid, time.Minute); err != nil {
+			return diag.FromErr(err)
+		}
+		if err := waitVmStatus(ctx, *config, location, uuid, "running", d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return diag.FromErr(err)
 		}
 	}
+
 	return vmRead(ctx, d, m)
 }
 
@@ -627,5 +1394,20 @@ func vmDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.D
 	}
 	defer resp.Body.Close()
 
+	// deletion is asynchronous, networks & disks are only released once the vm is gone
+	err = retry.RetryContext(ctx, d.Timeout(schema.TimeoutDelete), func() *retry.RetryError {
+		vm, err := getVm(*config, location, uuid)
+		if err != nil {
+			return retry.NonRetryableError(err)
+		}
+		if vm != nil {
+			return retry.RetryableError(fmt.Errorf("vm %s is still being deleted", uuid))
+		}
+		return nil
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
 	return nil
 }
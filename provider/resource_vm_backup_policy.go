@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"terraform-provider-idcloudhost/provider/schemas"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceVmBackupPolicy() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: vmBackupPolicyCreate,
+		ReadContext:   vmBackupPolicyRead,
+		UpdateContext: vmBackupPolicyUpdate,
+		DeleteContext: vmBackupPolicyDelete,
+		Schema:        schemas.VmBackupPolicySchema,
+		Importer: &schema.ResourceImporter{
+			State: vmBackupPolicyState,
+		},
+	}
+}
+
+func putVmBackupPolicy(c Config, location string, vm_uuid string, enabled bool, schedule string, retention int) error {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/backup/policy"
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	form := url.Values{}
+	form.Add("uuid", vm_uuid)
+	form.Add("enabled", strconv.FormatBool(enabled))
+	form.Add("schedule", schedule)
+	form.Add("retention", strconv.Itoa(retention))
+	req, err := http.NewRequest("PUT", fullUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.PostForm = form
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(string(bodyBytes))
+	}
+
+	return nil
+}
+
+// id = VM UUID. only accept location from provider config
+func vmBackupPolicyState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
+	d.Set("vm_uuid", d.Id())
+	d.Set("location", config.DefaultLocation)
+
+	diags := vmBackupPolicyRead(context.Background(), d, m)
+	if diags.HasError() {
+		return nil, fmt.Errorf(diags[0].Summary)
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func vmBackupPolicyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	vm_uuid := d.Get("vm_uuid").(string)
+
+	err := putVmBackupPolicy(*config, location, vm_uuid,
+		d.Get("enabled").(bool),
+		d.Get("schedule").(string),
+		d.Get("retention").(int),
+	)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(vm_uuid)
+
+	return vmBackupPolicyRead(ctx, d, m)
+}
+
+func vmBackupPolicyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/backup/policy"
+	generatedUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		generatedUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		generatedUrl = baseUrl + version + "/" + location + path
+	}
+
+	fullUrl, err := url.Parse(generatedUrl)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	client := &http.Client{}
+	queryParams := url.Values{}
+	queryParams.Add("uuid", d.Id())
+	fullUrl.RawQuery = queryParams.Encode()
+	req, err := http.NewRequest("GET", fullUrl.String(), nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// vm removed outside terraform
+		d.SetId("")
+		return nil
+	}
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	enabled, _ := result["enabled"].(bool)
+	schedule, _ := result["schedule"].(string)
+	retention, _ := result["retention"].(float64)
+
+	d.Set("vm_uuid", d.Id())
+	d.Set("enabled", enabled)
+	d.Set("schedule", schedule)
+	d.Set("retention", int(retention))
+
+	return nil
+}
+
+func vmBackupPolicyUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+
+	if d.HasChanges("enabled", "schedule", "retention") {
+		err := putVmBackupPolicy(*config, location, d.Id(),
+			d.Get("enabled").(bool),
+			d.Get("schedule").(string),
+			d.Get("retention").(int),
+		)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return vmBackupPolicyRead(ctx, d, m)
+}
+
+// disable backup, existing restore points are kept by the platform
+func vmBackupPolicyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+
+	err := putVmBackupPolicy(*config, location, d.Id(),
+		false,
+		d.Get("schedule").(string),
+		d.Get("retention").(int),
+	)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"terraform-provider-idcloudhost/provider/schemas"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceVmDisk() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: vmDiskCreate,
+		ReadContext:   vmDiskRead,
+		UpdateContext: vmDiskUpdate,
+		DeleteContext: vmDiskDelete,
+		Schema:        schemas.VmDiskSchema,
+		Importer: &schema.ResourceImporter{
+			State: vmDiskState,
+		},
+		CustomizeDiff: vmDiskSizeCustomizeDiff,
+	}
+}
+
+// disk can only grow online, shrinking require a new empty disk
+func vmDiskSizeCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	if d.Id() == "" || !d.HasChange("size") || !d.NewValueKnown("size") {
+		return nil
+	}
+	oldIntrface, newIntrface := d.GetChange("size")
+	oldSize := oldIntrface.(int)
+	newSize := newIntrface.(int)
+	if newSize >= oldSize {
+		return nil
+	}
+	if d.Get("allow_shrink_replace").(bool) {
+		return d.ForceNew("size")
+	}
+
+	return fmt.Errorf("size can not be decreased from %d to %d GB. set allow_shrink_replace = true to replace the disk with a new empty one", oldSize, newSize)
+}
+
+func findVmDisk(vm map[string]interface{}, disk_uuid string) map[string]interface{} {
+	if storageArray, ok := vm["storage"].([]interface{}); ok {
+		for _, disk := range storageArray {
+			if diskMap, ok := disk.(map[string]interface{}); ok {
+				if uuid, _ := diskMap["uuid"].(string); uuid == disk_uuid {
+					return diskMap
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// serialize disk attach per vm, parallel creates can't tell their new disks apart otherwise
+var vmDiskLocks sync.Map
+
+func lockVmDisks(vm_uuid string) func() {
+	lock, _ := vmDiskLocks.LoadOrStore(vm_uuid, &sync.Mutex{})
+	mutex := lock.(*sync.Mutex)
+	mutex.Lock()
+
+	return mutex.Unlock
+}
+
+// method = "POST" (attach) | "PATCH" (resize) | "DELETE" (detach).
+// return the decoded response body, nil when it is empty or not an object
+func vmDiskRequest(c Config, location string, method string, form url.Values) (map[string]interface{}, error) {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/storage"
+	fullUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	req, err := http.NewRequest(method, fullUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.PostForm = form
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf(string(bodyBytes))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// id = VM_UUID/DISK_UUID. only accept location from provider config
+func vmDiskState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("unexpected import id %q, expected VM_UUID/DISK_UUID", d.Id())
+	}
+
+	d.SetId(parts[1])
+	d.Set("vm_uuid", parts[0])
+	d.Set("location", config.DefaultLocation)
+
+	diags := vmDiskRead(context.Background(), d, m)
+	if diags.HasError() {
+		return nil, fmt.Errorf(diags[0].Summary)
+	}
+	if d.Id() == "" {
+		return nil, fmt.Errorf("disk not found")
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func vmDiskCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	vm_uuid := d.Get("vm_uuid").(string)
+	size := d.Get("size").(int)
+
+	unlock := lockVmDisks(vm_uuid)
+	defer unlock()
+
+	// remember existing disks to find the attached one when the response doesnt tell
+	vm, err := getVm(*config, location, vm_uuid)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if vm == nil {
+		return diag.FromErr(fmt.Errorf("vm %s not found", vm_uuid))
+	}
+	existing := map[string]bool{}
+	if storageArray, ok := vm["storage"].([]interface{}); ok {
+		for _, disk := range storageArray {
+			if diskMap, ok := disk.(map[string]interface{}); ok {
+				uuid, _ := diskMap["uuid"].(string)
+				existing[uuid] = true
+			}
+		}
+	}
+
+	form := url.Values{}
+	form.Add("uuid", vm_uuid)
+	form.Add("size_gb", strconv.Itoa(size))
+	result, err := vmDiskRequest(*config, location, "POST", form)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// response is the attached disk
+	disk_uuid, _ := result["uuid"].(string)
+	if disk_uuid == vm_uuid || existing[disk_uuid] {
+		disk_uuid = ""
+	}
+	if disk_uuid == "" {
+		// fallback, the only new disk since the lock is held
+		vm, err = getVm(*config, location, vm_uuid)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if storageArray, ok := vm["storage"].([]interface{}); ok {
+			for _, disk := range storageArray {
+				if diskMap, ok := disk.(map[string]interface{}); ok {
+					uuid, _ := diskMap["uuid"].(string)
+					if uuid != "" && !existing[uuid] {
+						disk_uuid = uuid
+						break
+					}
+				}
+			}
+		}
+	}
+	if disk_uuid == "" {
+		return diag.FromErr(fmt.Errorf("fail to get generated storage UUID"))
+	}
+
+	d.SetId(disk_uuid)
+	d.Set("disk_uuid", disk_uuid)
+
+	return vmDiskRead(ctx, d, m)
+}
+
+func vmDiskRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	vm_uuid := d.Get("vm_uuid").(string)
+
+	vm, err := getVm(*config, location, vm_uuid)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if vm == nil {
+		// vm removed outside terraform
+		d.SetId("")
+		return nil
+	}
+	disk := findVmDisk(vm, d.Id())
+	if disk == nil {
+		// disk detached outside terraform
+		d.SetId("")
+		return nil
+	}
+
+	size, _ := disk["size"].(float64)
+
+	d.Set("disk_uuid", d.Id())
+	d.Set("size", int(size))
+
+	return nil
+}
+
+func vmDiskUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+
+	if d.HasChange("size") {
+		form := url.Values{}
+		form.Add("uuid", d.Get("vm_uuid").(string))
+		form.Add("disk_uuid", d.Id())
+		form.Add("size_gb", strconv.Itoa(d.Get("size").(int)))
+		if _, err := vmDiskRequest(*config, location, "PATCH", form); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return vmDiskRead(ctx, d, m)
+}
+
+func vmDiskDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+
+	form := url.Values{}
+	form.Add("uuid", d.Get("vm_uuid").(string))
+	form.Add("disk_uuid", d.Id())
+	if _, err := vmDiskRequest(*config, location, "DELETE", form); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
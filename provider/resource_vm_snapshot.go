@@ -0,0 +1,278 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"terraform-provider-idcloudhost/provider/schemas"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func ResourceVmSnapshot() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: vmSnapshotCreate,
+		ReadContext:   vmSnapshotRead,
+		DeleteContext: vmSnapshotDelete,
+		Schema:        schemas.VmSnapshotSchema,
+		Importer: &schema.ResourceImporter{
+			State: vmSnapshotState,
+		},
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(30 * time.Minute),
+		},
+	}
+}
+
+func listVmSnapshots(c Config, location string, vm_uuid string) ([]interface{}, error) {
+	apiKey := c.ApiKey
+	baseUrl := c.BaseUrl
+	defaultLocation := c.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/snapshots"
+	generatedUrl := baseUrl + version + path
+	if defaultLocation != "" {
+		generatedUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		generatedUrl = baseUrl + version + "/" + location + path
+	}
+
+	fullUrl, err := url.Parse(generatedUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+	queryParams := url.Values{}
+	queryParams.Add("uuid", vm_uuid)
+	fullUrl.RawQuery = queryParams.Encode()
+	req, err := http.NewRequest("GET", fullUrl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf(string(bodyBytes))
+	}
+	var result []interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func findVmSnapshot(c Config, location string, vm_uuid string, snapshot_uuid string) (map[string]interface{}, error) {
+	snapshots, err := listVmSnapshots(c, location, vm_uuid)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshotMap, ok := snapshot.(map[string]interface{}); ok {
+			if uuid, _ := snapshotMap["uuid"].(string); uuid == snapshot_uuid {
+				return snapshotMap, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func setVmSnapshot(d *schema.ResourceData, snapshot map[string]interface{}) {
+	snapshot_uuid, _ := snapshot["uuid"].(string)
+	disks_uuid, _ := snapshot["disk_uuid"].(string)
+	size, _ := snapshot["size"].(float64)
+	created_at, _ := snapshot["created_at"].(string)
+	status, _ := snapshot["status"].(string)
+
+	d.Set("snapshot_uuid", snapshot_uuid)
+	d.Set("disks_uuid", disks_uuid)
+	d.Set("size", int(size))
+	d.Set("created_at", created_at)
+	d.Set("status", status)
+}
+
+// id = VM_UUID/SNAPSHOT_UUID. only accept location from provider config
+func vmSnapshotState(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+	config := m.(*Config)
+	parts := strings.Split(d.Id(), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("unexpected import id %q, expected VM_UUID/SNAPSHOT_UUID", d.Id())
+	}
+
+	d.SetId(parts[1])
+	d.Set("vm_uuid", parts[0])
+	d.Set("location", config.DefaultLocation)
+
+	diags := vmSnapshotRead(context.Background(), d, m)
+	if diags.HasError() {
+		return nil, fmt.Errorf(diags[0].Summary)
+	}
+	if d.Id() == "" {
+		return nil, fmt.Errorf("snapshot not found")
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func vmSnapshotCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/snapshot"
+	fullUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	vm_uuid := d.Get("vm_uuid").(string)
+	disks_uuid := d.Get("disks_uuid").(string)
+
+	client := &http.Client{}
+	form := url.Values{}
+	form.Add("uuid", vm_uuid)
+	if disks_uuid != "" {
+		form.Add("disk_uuid", disks_uuid)
+	}
+	req, err := http.NewRequest("POST", fullUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.PostForm = form
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	}
+	var result map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	snapshot_uuid, ok := result["uuid"].(string)
+	if !ok {
+		return diag.FromErr(fmt.Errorf("fail to get generated snapshot UUID"))
+	}
+
+	d.SetId(snapshot_uuid)
+	d.Set("snapshot_uuid", snapshot_uuid)
+
+	// wait until snapshot is completed
+	stateConf := &retry.StateChangeConf{
+		Pending: []string{"pending", "creating"},
+		Target:  []string{"available"},
+		Refresh: func() (interface{}, string, error) {
+			snapshot, err := findVmSnapshot(*config, location, vm_uuid, snapshot_uuid)
+			if err != nil {
+				return nil, "", err
+			}
+			if snapshot == nil {
+				return nil, "", nil
+			}
+			status, _ := snapshot["status"].(string)
+			return snapshot, status, nil
+		},
+		Timeout:    d.Timeout(schema.TimeoutCreate),
+		Delay:      5 * time.Second,
+		MinTimeout: 5 * time.Second,
+	}
+	if _, err := stateConf.WaitForStateContext(ctx); err != nil {
+		return diag.FromErr(fmt.Errorf("error waiting for snapshot %s: %s", snapshot_uuid, err))
+	}
+
+	return vmSnapshotRead(ctx, d, m)
+}
+
+func vmSnapshotRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	location := d.Get("location").(string)
+	vm_uuid := d.Get("vm_uuid").(string)
+
+	snapshot, err := findVmSnapshot(*config, location, vm_uuid, d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if snapshot == nil {
+		// snapshot removed outside terraform
+		d.SetId("")
+		return nil
+	}
+
+	setVmSnapshot(d, snapshot)
+
+	return nil
+}
+
+func vmSnapshotDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	config := m.(*Config)
+	apiKey := config.ApiKey
+	baseUrl := config.BaseUrl
+	defaultLocation := config.DefaultLocation
+	version := "/v1"
+	path := "/user-resource/vm/snapshot"
+	fullUrl := baseUrl + version + path
+	location := d.Get("location").(string)
+	if defaultLocation != "" {
+		fullUrl = baseUrl + version + "/" + defaultLocation + path
+	}
+	if location != "" {
+		fullUrl = baseUrl + version + "/" + location + path
+	}
+
+	client := &http.Client{}
+	form := url.Values{}
+	form.Add("uuid", d.Get("vm_uuid").(string))
+	form.Add("snapshot_uuid", d.Id())
+	req, err := http.NewRequest("DELETE", fullUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	req.PostForm = form
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("apikey", apiKey)
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 || resp.StatusCode < 200 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return diag.FromErr(fmt.Errorf(string(bodyBytes)))
+	}
+
+	return nil
+}
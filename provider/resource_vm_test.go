@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func testVmDisksDiff(t *testing.T, disks int, allowShrinkReplace bool) (*terraform.InstanceDiff, error) {
+	t.Helper()
+	state := &terraform.InstanceState{
+		ID: "vm-uuid",
+		Attributes: map[string]string{
+			"id":                        "vm-uuid",
+			"name":                      "myvm",
+			"location":                  "jkt01",
+			"username":                  "user",
+			"password":                  "Password123",
+			"os_name":                   "ubuntu",
+			"os_version":                "22.04-lts",
+			"vcpu":                      "1",
+			"ram":                       "1024",
+			"disks":                     "40",
+			"private_network_uuid":      "network-uuid",
+			"reserve_public_ip":         "false",
+			"generate_password":         "false",
+			"rebuild_on_os_change":      "false",
+			"allow_disk_shrink_replace": "false",
+			"allow_stopping_for_update": "false",
+			"reboot_type":               "soft",
+		},
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":                      "myvm",
+		"location":                  "jkt01",
+		"username":                  "user",
+		"password":                  "Password123",
+		"os_name":                   "ubuntu",
+		"os_version":                "22.04-lts",
+		"vcpu":                      1,
+		"ram":                       1024,
+		"disks":                     disks,
+		"private_network_uuid":      "network-uuid",
+		"allow_disk_shrink_replace": allowShrinkReplace,
+	})
+
+	return ResourceVm().Diff(context.Background(), state, config, &Config{})
+}
+
+func TestVmDisksCustomizeDiffGrow(t *testing.T) {
+	diff, err := testVmDisksDiff(t, 60, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff == nil || diff.Attributes["disks"] == nil {
+		t.Fatalf("expected a disks diff")
+	}
+	if diff.RequiresNew() {
+		t.Fatalf("growing disks should update the vm in place")
+	}
+}
+
+func TestVmDisksCustomizeDiffShrink(t *testing.T) {
+	_, err := testVmDisksDiff(t, 20, false)
+	if err == nil {
+		t.Fatalf("expected an error when shrinking disks")
+	}
+	if !strings.Contains(err.Error(), "allow_disk_shrink_replace") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestVmDisksCustomizeDiffShrinkReplace(t *testing.T) {
+	diff, err := testVmDisksDiff(t, 20, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff == nil || !diff.RequiresNew() {
+		t.Fatalf("shrinking disks with allow_disk_shrink_replace should force a new vm")
+	}
+	if !diff.Attributes["disks"].RequiresNew {
+		t.Fatalf("expected disks to force a new vm")
+	}
+}
@@ -0,0 +1,121 @@
+package schemas
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+var firewallProtocols = []string{"tcp", "udp", "icmp", "any"}
+
+// omitted port_end is sent as port_start and read back as such
+func suppressDefaultPortEnd(k, old, new string, d *schema.ResourceData) bool {
+	if new != "" && new != "0" {
+		return false
+	}
+	port_start, _ := d.Get(strings.TrimSuffix(k, "port_end") + "port_start").(int)
+
+	return old == strconv.Itoa(port_start)
+}
+
+var FirewallSchema = map[string]*schema.Schema{
+	"uuid": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"name": {
+		Type:     schema.TypeString,
+		Required: true,
+	},
+	"location": {
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+		ForceNew: true,
+	},
+	"ingress": {
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"protocol": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice(firewallProtocols, false),
+				},
+				"port_start": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IsPortNumber,
+				},
+				// defaults to port_start
+				"port_end": {
+					Type:             schema.TypeInt,
+					Optional:         true,
+					ValidateFunc:     validation.IsPortNumber,
+					DiffSuppressFunc: suppressDefaultPortEnd,
+				},
+				"source_cidr": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "0.0.0.0/0",
+					ValidateFunc: validation.IsCIDR,
+				},
+			},
+		},
+	},
+	"egress": {
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"protocol": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice(firewallProtocols, false),
+				},
+				"port_start": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IsPortNumber,
+				},
+				// defaults to port_start
+				"port_end": {
+					Type:             schema.TypeInt,
+					Optional:         true,
+					ValidateFunc:     validation.IsPortNumber,
+					DiffSuppressFunc: suppressDefaultPortEnd,
+				},
+				"destination_cidr": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "0.0.0.0/0",
+					ValidateFunc: validation.IsCIDR,
+				},
+			},
+		},
+	},
+}
+
+var FirewallAttachmentSchema = map[string]*schema.Schema{
+	"firewall_uuid": {
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	},
+	"vm_uuids": {
+		Type:     schema.TypeSet,
+		Required: true,
+		Elem: &schema.Schema{
+			Type: schema.TypeString,
+		},
+	},
+	"location": {
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+		ForceNew: true,
+	},
+}
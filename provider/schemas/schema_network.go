@@ -1,9 +1,14 @@
 package schemas
 
 import (
+	"regexp"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
+var hostnameRegexp = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,63}\.?$`)
+
 var PrivateNteworkSchema = map[string]*schema.Schema{
 	"name": {
 		Type:     schema.TypeString,
@@ -16,6 +21,56 @@ var PrivateNteworkSchema = map[string]*schema.Schema{
 	"location": {
 		Type:     schema.TypeString,
 		Optional: true,
+		Computed: true,
+	},
+	// platform picks the range & dns when unset
+	"cidr": {
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ForceNew:     true,
+		ValidateFunc: validation.IsCIDRNetwork(8, 30),
+	},
+	"gateway": {
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ForceNew:     true,
+		RequiredWith: []string{"cidr"},
+		ValidateFunc: validation.IsIPv4Address,
+	},
+	"dns_servers": {
+		Type:     schema.TypeList,
+		Optional: true,
+		Computed: true,
+		Elem: &schema.Schema{
+			Type:         schema.TypeString,
+			ValidateFunc: validation.IsIPv4Address,
+		},
+	},
+	"subnet": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"vm_uuids": {
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Schema{
+			Type: schema.TypeString,
+		},
+	},
+	"is_default": {
+		Type:     schema.TypeBool,
+		Computed: true,
+	},
+	// false never unsets the default, there is nothing to apply
+	"set_as_default": {
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+		DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+			return new == "false"
+		},
 	},
 }
 
@@ -32,8 +87,50 @@ var FloatIpSchema = map[string]*schema.Schema{
 		Type:     schema.TypeString,
 		Computed: true,
 	},
+	"assigned_to": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"assigned_to_resource_type": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	// empty removes the PTR record
+	"reverse_dns": {
+		Type:     schema.TypeString,
+		Optional: true,
+		ValidateFunc: validation.Any(
+			validation.StringIsEmpty,
+			validation.StringMatch(hostnameRegexp, "must be a valid hostname, e.g. mail.example.com"),
+		),
+	},
+	"force_unassign": {
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	},
 	"location": {
 		Type:     schema.TypeString,
 		Optional: true,
+		Computed: true,
+	},
+}
+
+var FloatIpAssociationSchema = map[string]*schema.Schema{
+	"address": {
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	},
+	"vm_uuid": {
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	},
+	"location": {
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+		ForceNew: true,
 	},
 }
@@ -2,6 +2,7 @@ package schemas
 
 import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
 var VmSchema = map[string]*schema.Schema{
@@ -13,9 +14,35 @@ var VmSchema = map[string]*schema.Schema{
 		Type:     schema.TypeString,
 		Computed: true,
 	},
+	"private_ipv4": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"mac_address": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"public_ipv4": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"hostname": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"created_at": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"status": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
 	"location": {
 		Type:     schema.TypeString,
 		Optional: true,
+		Computed: true,
+		ForceNew: true,
 	},
 	"name": {
 		Type:     schema.TypeString,
@@ -28,41 +55,307 @@ var VmSchema = map[string]*schema.Schema{
 	"username": {
 		Type:     schema.TypeString,
 		Required: true,
+		ForceNew: true,
 	},
 	"password": {
-		Type:     schema.TypeString,
-		Required: true,
+		Type:      schema.TypeString,
+		Optional:  true,
+		Sensitive: true,
+	},
+	"generate_password": {
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	},
+	"generated_password": {
+		Type:      schema.TypeString,
+		Computed:  true,
+		Sensitive: true,
 	},
 	"os_name": {
-		Type:     schema.TypeString,
-		Required: true,
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ExactlyOneOf: []string{"os_name", "source_snapshot_uuid", "clone_from_vm_uuid"},
+		RequiredWith: []string{"os_version"},
 	},
 	"os_version": {
-		Type:     schema.TypeString,
-		Required: true,
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		RequiredWith: []string{"os_name"},
+	},
+	"rebuild_on_os_change": {
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	},
+	"source_snapshot_uuid": {
+		Type:          schema.TypeString,
+		Optional:      true,
+		ForceNew:      true,
+		ConflictsWith: []string{"os_name", "os_version", "clone_from_vm_uuid"},
+	},
+	"clone_from_vm_uuid": {
+		Type:          schema.TypeString,
+		Optional:      true,
+		ForceNew:      true,
+		ConflictsWith: []string{"os_name", "os_version", "source_snapshot_uuid"},
 	},
 	"vcpu": {
-		Type:     schema.TypeInt,
-		Required: true,
+		Type:         schema.TypeInt,
+		Required:     true,
+		ValidateFunc: validation.IntAtLeast(1),
 	},
+	// mb, in 512 mb steps
 	"ram": {
+		Type:         schema.TypeInt,
+		Required:     true,
+		ValidateFunc: validation.All(validation.IntAtLeast(1024), validation.IntDivisibleBy(512)),
+	},
+	// gb
+	"disks": {
+		Type:         schema.TypeInt,
+		Required:     true,
+		ValidateFunc: validation.IntAtLeast(20),
+	},
+	"allow_disk_shrink_replace": {
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	},
+	"private_network_uuid": {
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ForceNew:     true,
+		ExactlyOneOf: []string{"private_network_uuid", "network_interface"},
+	},
+	"network_interface": {
+		Type:     schema.TypeList,
+		Optional: true,
+		MinItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"network_uuid": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"fixed_ip": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					ValidateFunc: validation.IsIPv4Address,
+				},
+			},
+		},
+	},
+	"float_ip_address": {
+		Type:          schema.TypeString,
+		Optional:      true,
+		ConflictsWith: []string{"reserve_public_ip"},
+	},
+	"reserve_public_ip": {
+		Type:          schema.TypeBool,
+		Optional:      true,
+		Default:       false,
+		ForceNew:      true,
+		ConflictsWith: []string{"float_ip_address"},
+	},
+	"desired_status": {
+		Type:         schema.TypeString,
+		Optional:     true,
+		ValidateFunc: validation.StringInSlice([]string{"running", "stopped", "force_stop"}, false),
+	},
+	"reboot_trigger": {
+		Type:     schema.TypeMap,
+		Optional: true,
+		Elem: &schema.Schema{
+			Type: schema.TypeString,
+		},
+	},
+	"reboot_type": {
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      "soft",
+		ValidateFunc: validation.StringInSlice([]string{"soft", "hard"}, false),
+	},
+	"allow_stopping_for_update": {
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	},
+	"user_data": {
+		Type:     schema.TypeString,
+		Optional: true,
+		ForceNew: true,
+	},
+	"ssh_keys": {
+		Type:     schema.TypeList,
+		Optional: true,
+		ForceNew: true,
+		Elem: &schema.Schema{
+			Type: schema.TypeString,
+		},
+	},
+}
+
+var VmSnapshotSchema = map[string]*schema.Schema{
+	"snapshot_uuid": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"vm_uuid": {
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	},
+	"disks_uuid": {
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+		ForceNew: true,
+	},
+	"location": {
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+		ForceNew: true,
+	},
+	"size": {
 		Type:     schema.TypeInt,
+		Computed: true,
+	},
+	"created_at": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"status": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+}
+
+var DataVmSnapshotSchema = map[string]*schema.Schema{
+	"vm_uuid": {
+		Type:     schema.TypeString,
 		Required: true,
 	},
-	"disks": {
+	"location": {
+		Type:     schema.TypeString,
+		Optional: true,
+	},
+	"snapshot_uuid": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"disks_uuid": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"size": {
 		Type:     schema.TypeInt,
+		Computed: true,
+	},
+	"created_at": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"status": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+}
+
+var VmBackupPolicySchema = map[string]*schema.Schema{
+	"vm_uuid": {
+		Type:     schema.TypeString,
 		Required: true,
+		ForceNew: true,
 	},
-	"private_network_uuid": {
+	"location": {
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+		ForceNew: true,
+	},
+	"enabled": {
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  true,
+	},
+	"schedule": {
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      "daily",
+		ValidateFunc: validation.StringInSlice([]string{"daily", "weekly", "monthly"}, false),
+	},
+	"retention": {
+		Type:         schema.TypeInt,
+		Optional:     true,
+		Default:      7,
+		ValidateFunc: validation.IntAtLeast(1),
+	},
+}
+
+var DataVmBackupsSchema = map[string]*schema.Schema{
+	"vm_uuid": {
 		Type:     schema.TypeString,
 		Required: true,
 	},
-	"float_ip_address": {
+	"location": {
 		Type:     schema.TypeString,
 		Optional: true,
 	},
-	"desired_status": {
+	"backups": {
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"backup_uuid": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"size": {
+					Type:     schema.TypeInt,
+					Computed: true,
+				},
+				"created_at": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"status": {
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+			},
+		},
+	},
+}
+
+var VmDiskSchema = map[string]*schema.Schema{
+	"disk_uuid": {
+		Type:     schema.TypeString,
+		Computed: true,
+	},
+	"vm_uuid": {
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	},
+	"size": {
+		Type:         schema.TypeInt,
+		Required:     true,
+		ValidateFunc: validation.IntAtLeast(1),
+	},
+	"allow_shrink_replace": {
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	},
+	"location": {
 		Type:     schema.TypeString,
 		Optional: true,
+		Computed: true,
+		ForceNew: true,
 	},
 }